@@ -2,6 +2,7 @@ package lib
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
@@ -19,13 +20,65 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
+// preCheckpointDir is the subdirectory of ctr.Dir() used to store the
+// memory pages produced by a CRIU pre-dump (PreCheckPoint).
+const preCheckpointDir = "pre-checkpoint"
+
+// parentDumpFile records the directory of the pre-checkpoint a final
+// checkpoint was built on top of, so restore knows to layer the images.
+const parentDumpFile = "parent.dump"
+
 type ContainerCheckpointRestoreOptions struct {
 	Container string
 	Pod       string
 
+	// PreCheckPoint instructs the runtime to only perform a CRIU pre-dump.
+	// The container keeps running and only its memory pages are dumped,
+	// which can be replayed later by one or more follow-up pre-dumps or a
+	// final checkpoint to shrink the downtime of a live migration.
+	PreCheckPoint bool
+	// WithPrevious marks a (final) checkpoint as building on a previous
+	// pre-checkpoint. CRIU is pointed at ParentImage via --prev-images-dir
+	// and the parent directory is recorded in the checkpoint metadata so
+	// restore knows to layer the images.
+	WithPrevious bool
+	// ParentImage is the directory of the previous pre-checkpoint to use
+	// as the parent images directory for this checkpoint.
+	ParentImage string
+	// ImportPrevious is the path to an archive of a previous pre-checkpoint.
+	// It is unpacked under a parent/ subdirectory of ctr.Dir(). Restore
+	// passes that directory to CRIU as --prev-images-dir when the main
+	// checkpoint records a parent (parent.dump) and ParentImage is empty.
+	ImportPrevious string
+	// IgnoreRootFS skips exporting the container's rootfs diff, producing a
+	// smaller archive for operators who manage the rootfs state themselves.
+	IgnoreRootFS bool
+	// IgnoreVolumes skips archiving the contents of bind mounts that
+	// resolve to named container volumes, for operators whose volume data
+	// already lives on a shared filesystem.
+	IgnoreVolumes bool
+	// Compression selects the archive compression algorithm: "none" (the
+	// default), "gzip" or "zstd".
+	Compression string
+
 	libpod.ContainerCheckpointOptions
 }
 
+// compressionFromString maps the user-facing Compression option to the
+// archive.Compression the tar writer understands.
+func compressionFromString(compression string) (archive.Compression, error) {
+	switch compression {
+	case "", "none":
+		return archive.Uncompressed, nil
+	case "gzip":
+		return archive.Gzip, nil
+	case "zstd":
+		return archive.Zstd, nil
+	default:
+		return archive.Uncompressed, fmt.Errorf("unknown checkpoint compression %q", compression)
+	}
+}
+
 // ContainerCheckpoint checkpoints a running container.
 func (c *ContainerServer) ContainerCheckpoint(ctx context.Context, opts *ContainerCheckpointRestoreOptions) (string, error) {
 	ctr, err := c.LookupContainer(opts.Container)
@@ -44,17 +97,39 @@ func (c *ContainerServer) ContainerCheckpoint(ctx context.Context, opts *Contain
 		return "", fmt.Errorf("container %s is not running", ctr.ID())
 	}
 
-	if opts.TargetFile != "" {
-		if err := c.prepareCheckpointExport(ctr); err != nil {
+	if (opts.PreCheckPoint || opts.WithPrevious) && !c.runtime.RuntimeSupportsCheckpointPreDump(ctr.Sandbox()) {
+		return "", fmt.Errorf("runtime for container %s does not support CRIU pre-dump", ctr.ID())
+	}
+
+	if opts.PreCheckPoint {
+		if err := os.MkdirAll(filepath.Join(ctr.Dir(), preCheckpointDir), 0o700); err != nil {
+			return "", fmt.Errorf("failed to create pre-checkpoint directory for container %s: %w", ctr.ID(), err)
+		}
+	} else if opts.TargetFile != "" {
+		if err := c.prepareCheckpointExport(ctr, opts); err != nil {
 			return "", fmt.Errorf("failed to write config dumps for container %s: %w", ctr.ID(), err)
 		}
 	}
 
-	if err := c.runtime.CheckpointContainer(ctx, ctr, specgen.Config, opts.KeepRunning); err != nil {
+	if err := c.runtime.CheckpointContainer(ctx, ctr, specgen.Config, opts.KeepRunning, opts.PreCheckPoint, opts.ParentImage); err != nil {
 		return "", fmt.Errorf("failed to checkpoint container %s: %w", ctr.ID(), err)
 	}
+
+	// A pre-dump leaves the container running and does not produce a
+	// self-contained checkpoint archive, so none of the remaining
+	// finalization steps (rootfs export, stopping the container) apply.
+	if opts.PreCheckPoint {
+		return ctr.ID(), nil
+	}
+
+	if opts.WithPrevious {
+		if _, err := metadata.WriteJSONFile(opts.ParentImage, ctr.Dir(), parentDumpFile); err != nil {
+			return "", fmt.Errorf("failed to record parent image for container %s: %w", ctr.ID(), err)
+		}
+	}
+
 	if opts.TargetFile != "" {
-		if err := c.exportCheckpoint(ctr, specgen.Config, opts.TargetFile); err != nil {
+		if err := c.exportCheckpoint(ctr, specgen.Config, opts); err != nil {
 			return "", fmt.Errorf("failed to write file system changes of container %s: %w", ctr.ID(), err)
 		}
 	}
@@ -138,10 +213,31 @@ type ExternalBindMount struct {
 	Permissions uint32 `json:"permissions"`
 }
 
+// namedVolumeSources returns the host paths of the CRI volumes attached to
+// the container, as recorded by the kubelet in the annotations.Volumes
+// annotation.
+func namedVolumeSources(specgen *rspec.Spec) (map[string]bool, error) {
+	sources := map[string]bool{}
+	raw, ok := specgen.Annotations[annotations.Volumes]
+	if !ok || raw == "" {
+		return sources, nil
+	}
+	var volumes []struct {
+		HostPath string `json:"host_path"`
+	}
+	if err := json.Unmarshal([]byte(raw), &volumes); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal volumes annotation: %w", err)
+	}
+	for _, v := range volumes {
+		sources[v.HostPath] = true
+	}
+	return sources, nil
+}
+
 // prepareCheckpointExport writes the config and spec to
 // JSON files for later export
 // Podman: libpod/container_internal.go
-func (c *ContainerServer) prepareCheckpointExport(ctr *oci.Container) error {
+func (c *ContainerServer) prepareCheckpointExport(ctr *oci.Container, opts *ContainerCheckpointRestoreOptions) error {
 	// save spec
 	jsonPath := filepath.Join(ctr.BundlePath(), "config.json")
 	g, err := generate.NewFromFile(jsonPath)
@@ -164,12 +260,20 @@ func (c *ContainerServer) prepareCheckpointExport(ctr *oci.Container) error {
 			}
 			return c.config.DefaultRuntime
 		}(),
+		IgnoreRootFS:  opts.IgnoreRootFS,
+		IgnoreVolumes: opts.IgnoreVolumes,
+		Compression:   opts.Compression,
 	}
 
 	if _, err := metadata.WriteJSONFile(config, ctr.Dir(), metadata.ConfigDumpFile); err != nil {
 		return err
 	}
 
+	namedVolumes, err := namedVolumeSources(g.Config)
+	if err != nil {
+		return fmt.Errorf("failed to determine named volumes for container %q: %w", ctr.ID(), err)
+	}
+
 	// During container creation CRI-O creates all missing bind mount sources as
 	// directories. This is disabled during restore as CRIU requires the bind mount
 	// source to be of the same type. Directories need to be directories and regular
@@ -187,6 +291,9 @@ func (c *ContainerServer) prepareCheckpointExport(ctr *oci.Container) error {
 		if m.Type != bindMount {
 			continue
 		}
+		if opts.IgnoreVolumes && namedVolumes[m.Source] {
+			continue
+		}
 		fileInfo, err := os.Stat(m.Source)
 		if err != nil {
 			return fmt.Errorf("unable to stat() %q: %w", m.Source, err)
@@ -217,7 +324,7 @@ func (c *ContainerServer) prepareCheckpointExport(ctr *oci.Container) error {
 	return nil
 }
 
-func (c *ContainerServer) exportCheckpoint(ctr *oci.Container, specgen *rspec.Spec, export string) error {
+func (c *ContainerServer) exportCheckpoint(ctr *oci.Container, specgen *rspec.Spec, opts *ContainerCheckpointRestoreOptions) error {
 	id := ctr.ID()
 	dest := ctr.Dir()
 	logrus.Debugf("Exporting checkpoint image of container %q to %q", id, dest)
@@ -230,24 +337,29 @@ func (c *ContainerServer) exportCheckpoint(ctr *oci.Container, specgen *rspec.Sp
 		metadata.SpecDumpFile,
 		"bind.mounts",
 	}
-
-	// To correctly track deleted files, let's go through the output of 'podman diff'
-	rootFsChanges, err := c.getDiff(id, specgen)
-	if err != nil {
-		return fmt.Errorf("error exporting root file-system diff for %q: %w", id, err)
+	if opts.WithPrevious {
+		includeFiles = append(includeFiles, parentDumpFile)
 	}
-	mountPoint, err := c.StorageImageServer().GetStore().Mount(id, specgen.Linux.MountLabel)
-	if err != nil {
-		return fmt.Errorf("not able to get mountpoint for container %q: %w", id, err)
-	}
-	addToTarFiles, err := crutils.CRCreateRootFsDiffTar(&rootFsChanges, mountPoint, dest)
-	if err != nil {
-		return err
+
+	var addToTarFiles []string
+	if !opts.IgnoreRootFS {
+		// To correctly track deleted files, let's go through the output of 'podman diff'
+		rootFsChanges, err := c.getDiff(id, specgen)
+		if err != nil {
+			return fmt.Errorf("error exporting root file-system diff for %q: %w", id, err)
+		}
+		mountPoint, err := c.StorageImageServer().GetStore().Mount(id, specgen.Linux.MountLabel)
+		if err != nil {
+			return fmt.Errorf("not able to get mountpoint for container %q: %w", id, err)
+		}
+		addToTarFiles, err = crutils.CRCreateRootFsDiffTar(&rootFsChanges, mountPoint, dest)
+		if err != nil {
+			return err
+		}
 	}
 
 	// Put log file into checkpoint archive
-	_, err = os.Stat(specgen.Annotations[annotations.LogPath])
-	if err == nil {
+	if _, err := os.Stat(specgen.Annotations[annotations.LogPath]); err == nil {
 		src, err := os.Open(specgen.Annotations[annotations.LogPath])
 		if err != nil {
 			return fmt.Errorf("error opening log file %q: %w", specgen.Annotations[annotations.LogPath], err)
@@ -268,9 +380,24 @@ func (c *ContainerServer) exportCheckpoint(ctr *oci.Container, specgen *rspec.Sp
 
 	includeFiles = append(includeFiles, addToTarFiles...)
 
+	manifest, err := buildCheckpointManifest(dest, includeFiles)
+	if err != nil {
+		return fmt.Errorf("error building checkpoint manifest for %q: %w", id, err)
+	}
+	manifestFiles, err := writeCheckpointManifest(dest, manifest, c.config.CheckpointSigningKey)
+	if err != nil {
+		return fmt.Errorf("error writing checkpoint manifest for %q: %w", id, err)
+	}
+	includeFiles = append(includeFiles, manifestFiles...)
+	addToTarFiles = append(addToTarFiles, manifestFiles...)
+
+	compression, err := compressionFromString(opts.Compression)
+	if err != nil {
+		return err
+	}
+
 	input, err := archive.TarWithOptions(ctr.Dir(), &archive.TarOptions{
-		// This should be configurable via api.proti
-		Compression:      archive.Uncompressed,
+		Compression:      compression,
 		IncludeSourceDir: true,
 		IncludeFiles:     includeFiles,
 	})
@@ -280,9 +407,9 @@ func (c *ContainerServer) exportCheckpoint(ctr *oci.Container, specgen *rspec.Sp
 
 	// The resulting tar archive should not be readable by everyone as it contains
 	// every memory page of the checkpointed processes.
-	outFile, err := os.OpenFile(export, os.O_RDWR|os.O_CREATE, 0o600)
+	outFile, err := os.OpenFile(opts.TargetFile, os.O_RDWR|os.O_CREATE, 0o600)
 	if err != nil {
-		return fmt.Errorf("error creating checkpoint export file %q: %w", export, err)
+		return fmt.Errorf("error creating checkpoint export file %q: %w", opts.TargetFile, err)
 	}
 	defer outFile.Close()
 
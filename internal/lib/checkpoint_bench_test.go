@@ -0,0 +1,72 @@
+package lib
+
+import (
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/containers/storage/pkg/archive"
+)
+
+// writeSyntheticMemoryDump creates a directory populated with files that
+// resemble a memory-heavy checkpoint: four large "pages-*.img" files filled
+// with pseudo-random data, as CRIU would produce for a container with a
+// sizeable RSS, plus a small spec.dump metadata file.
+func writeSyntheticMemoryDump(tb testing.TB, dir string) {
+	tb.Helper()
+
+	rnd := rand.New(rand.NewSource(42)) //nolint:gosec // deterministic benchmark data, not security sensitive
+
+	for i := 0; i < 4; i++ {
+		buf := make([]byte, 16*1024*1024)
+		if _, err := rnd.Read(buf); err != nil {
+			tb.Fatalf("failed to generate synthetic page data: %v", err)
+		}
+		path := filepath.Join(dir, "pages-"+string(rune('0'+i))+".img")
+		if err := os.WriteFile(path, buf, 0o600); err != nil {
+			tb.Fatalf("failed to write synthetic page file: %v", err)
+		}
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "spec.dump"), []byte(`{"ociVersion":"1.0.0"}`), 0o600); err != nil {
+		tb.Fatalf("failed to write synthetic spec dump: %v", err)
+	}
+}
+
+// BenchmarkCheckpointCompression compares archive size and wall-clock export
+// time across the compressors exposed via ContainerCheckpointRestoreOptions.Compression,
+// so the default can be picked with data rather than a guess.
+func BenchmarkCheckpointCompression(b *testing.B) {
+	dumpDir := b.TempDir()
+	writeSyntheticMemoryDump(b, dumpDir)
+
+	for _, compression := range []string{"none", "gzip", "zstd"} {
+		b.Run(compression, func(b *testing.B) {
+			method, err := compressionFromString(compression)
+			if err != nil {
+				b.Fatalf("unexpected compression %q: %v", compression, err)
+			}
+
+			var size int64
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				input, err := archive.TarWithOptions(dumpDir, &archive.TarOptions{
+					Compression:      method,
+					IncludeSourceDir: true,
+				})
+				if err != nil {
+					b.Fatalf("failed to tar synthetic dump: %v", err)
+				}
+
+				n, err := io.Copy(io.Discard, input)
+				if err != nil {
+					b.Fatalf("failed to read archive: %v", err)
+				}
+				size = n
+			}
+			b.ReportMetric(float64(size), "bytes/archive")
+		})
+	}
+}
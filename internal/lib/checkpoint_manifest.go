@@ -0,0 +1,251 @@
+package lib
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// checkpointManifestFile records the sha256 of every file placed into a
+// checkpoint archive, so tampering or corruption can be detected before the
+// runtime is asked to restore a container from it.
+const checkpointManifestFile = "checkpoint.manifest.json"
+
+// checkpointManifestSigFile is the detached ed25519 signature of
+// checkpointManifestFile, written only when the server is configured with a
+// CheckpointSigningKey.
+const checkpointManifestSigFile = "checkpoint.manifest.sig"
+
+// CheckpointManifest records the sha256 digest of every file bundled into a
+// checkpoint archive, keyed by the path relative to ctr.Dir().
+type CheckpointManifest struct {
+	Files map[string]string `json:"files"`
+}
+
+// CheckpointVerificationError is returned when a checkpoint archive fails
+// integrity or signature verification, so callers can distinguish tampering
+// or a stale signing key from ordinary I/O or decode errors.
+type CheckpointVerificationError struct {
+	Reason string
+}
+
+func (e *CheckpointVerificationError) Error() string {
+	return fmt.Sprintf("checkpoint verification failed: %s", e.Reason)
+}
+
+// hashFile returns the hex-encoded sha256 digest of path.
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// buildCheckpointManifest hashes every file that will be placed into the
+// checkpoint archive, expanding directories (such as the CRIU checkpoint
+// directory holding pages-*.img) into their individual files.
+func buildCheckpointManifest(dir string, includeFiles []string) (*CheckpointManifest, error) {
+	manifest := &CheckpointManifest{Files: map[string]string{}}
+
+	for _, name := range includeFiles {
+		path := filepath.Join(dir, name)
+		info, err := os.Stat(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+
+		if !info.IsDir() {
+			digest, err := hashFile(path)
+			if err != nil {
+				return nil, err
+			}
+			manifest.Files[name] = digest
+			continue
+		}
+
+		if err := filepath.Walk(path, func(walkPath string, walkInfo os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if walkInfo.IsDir() {
+				return nil
+			}
+			rel, err := filepath.Rel(dir, walkPath)
+			if err != nil {
+				return err
+			}
+			digest, err := hashFile(walkPath)
+			if err != nil {
+				return err
+			}
+			manifest.Files[rel] = digest
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	return manifest, nil
+}
+
+// writeCheckpointManifest writes the manifest to dir and, when signingKeyPath
+// is set, a detached ed25519 signature alongside it. It returns the list of
+// file names (relative to dir) that were written, to be merged into the
+// archive's include list.
+func writeCheckpointManifest(dir string, manifest *CheckpointManifest, signingKeyPath string) ([]string, error) {
+	encoded, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	manifestPath := filepath.Join(dir, checkpointManifestFile)
+	if err := os.WriteFile(manifestPath, encoded, 0o600); err != nil {
+		return nil, err
+	}
+	written := []string{checkpointManifestFile}
+
+	if signingKeyPath == "" {
+		return written, nil
+	}
+
+	key, err := loadEd25519PrivateKey(signingKeyPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load checkpoint signing key %q: %w", signingKeyPath, err)
+	}
+
+	signature := ed25519.Sign(key, encoded)
+	sigPath := filepath.Join(dir, checkpointManifestSigFile)
+	if err := os.WriteFile(sigPath, signature, 0o600); err != nil {
+		return nil, err
+	}
+	written = append(written, checkpointManifestSigFile)
+
+	return written, nil
+}
+
+// verifyCheckpointManifest re-hashes every file the manifest at dir claims
+// to cover, and, when publicKeyPath is set, verifies the detached signature
+// over the manifest. It returns a *CheckpointVerificationError when a file
+// is unreadable or its checksum differs, or when the manifest or signature
+// is missing or the signature is invalid, so callers can tell a failed
+// verification from other errors. A checksum mismatch reads the same
+// whether the file was tampered with or corrupted.
+func verifyCheckpointManifest(dir, publicKeyPath string) error {
+	manifestPath := filepath.Join(dir, checkpointManifestFile)
+	raw, err := os.ReadFile(manifestPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			if publicKeyPath != "" {
+				// A verification key is configured: an archive without a
+				// manifest cannot be authenticated, so treat it the same as
+				// a tampered one rather than silently accepting it. Only
+				// archives written before this feature (and only when no
+				// key is configured) get a pass.
+				return &CheckpointVerificationError{Reason: "missing checkpoint manifest"}
+			}
+			return nil
+		}
+		return err
+	}
+
+	var manifest CheckpointManifest
+	if err := json.Unmarshal(raw, &manifest); err != nil {
+		return err
+	}
+
+	for name, want := range manifest.Files {
+		got, err := hashFile(filepath.Join(dir, name))
+		if err != nil {
+			return &CheckpointVerificationError{Reason: fmt.Sprintf("unable to read %q: %v", name, err)}
+		}
+		if got != want {
+			return &CheckpointVerificationError{Reason: fmt.Sprintf("checksum mismatch for %q", name)}
+		}
+	}
+
+	if publicKeyPath == "" {
+		return nil
+	}
+
+	key, err := loadEd25519PublicKey(publicKeyPath)
+	if err != nil {
+		return fmt.Errorf("failed to load checkpoint verification key %q: %w", publicKeyPath, err)
+	}
+
+	signature, err := os.ReadFile(filepath.Join(dir, checkpointManifestSigFile))
+	if err != nil {
+		return &CheckpointVerificationError{Reason: fmt.Sprintf("missing checkpoint signature: %v", err)}
+	}
+
+	if !ed25519.Verify(key, raw, signature) {
+		return &CheckpointVerificationError{Reason: "checkpoint manifest signature is invalid"}
+	}
+
+	return nil
+}
+
+func loadEd25519PrivateKey(path string) (ed25519.PrivateKey, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM block found in %q", path)
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	priv, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%q does not contain an ed25519 private key", path)
+	}
+
+	return priv, nil
+}
+
+func loadEd25519PublicKey(path string) (ed25519.PublicKey, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM block found in %q", path)
+	}
+
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	pub, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("%q does not contain an ed25519 public key", path)
+	}
+
+	return pub, nil
+}
@@ -0,0 +1,90 @@
+package lib
+
+import (
+	"testing"
+
+	"github.com/containers/podman/v4/pkg/annotations"
+	"github.com/containers/storage/pkg/archive"
+	rspec "github.com/opencontainers/runtime-spec/specs-go"
+)
+
+func TestNamedVolumeSources(t *testing.T) {
+	for name, tc := range map[string]struct {
+		annotation string
+		want       map[string]bool
+		wantErr    bool
+	}{
+		"no annotation": {
+			annotation: "",
+			want:       map[string]bool{},
+		},
+		"single volume": {
+			annotation: `[{"host_path":"/var/lib/kubelet/pods/1/volumes/a"}]`,
+			want:       map[string]bool{"/var/lib/kubelet/pods/1/volumes/a": true},
+		},
+		"multiple volumes": {
+			annotation: `[{"host_path":"/a"},{"host_path":"/b"}]`,
+			want:       map[string]bool{"/a": true, "/b": true},
+		},
+		"invalid json": {
+			annotation: `not-json`,
+			wantErr:    true,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			specgen := &rspec.Spec{Annotations: map[string]string{}}
+			if tc.annotation != "" {
+				specgen.Annotations[annotations.Volumes] = tc.annotation
+			}
+
+			got, err := namedVolumeSources(specgen)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(got) != len(tc.want) {
+				t.Fatalf("expected %v, got %v", tc.want, got)
+			}
+			for source := range tc.want {
+				if !got[source] {
+					t.Errorf("expected %q to be a named volume source, got %v", source, got)
+				}
+			}
+		})
+	}
+}
+
+func TestCompressionFromString(t *testing.T) {
+	for name, tc := range map[string]struct {
+		compression string
+		want        archive.Compression
+		wantErr     bool
+	}{
+		"default":       {compression: "", want: archive.Uncompressed},
+		"explicit none": {compression: "none", want: archive.Uncompressed},
+		"gzip":          {compression: "gzip", want: archive.Gzip},
+		"zstd":          {compression: "zstd", want: archive.Zstd},
+		"unknown":       {compression: "bogus", wantErr: true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			got, err := compressionFromString(tc.compression)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error for compression %q, got none", tc.compression)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("expected %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
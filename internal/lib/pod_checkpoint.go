@@ -0,0 +1,298 @@
+package lib
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	metadata "github.com/checkpoint-restore/checkpointctl/lib"
+	"github.com/containers/storage/pkg/archive"
+	"github.com/cri-o/cri-o/internal/oci"
+	"github.com/sirupsen/logrus"
+)
+
+// podDumpFile records the sandbox identity, infra container and container
+// checkpoint order for a whole-pod checkpoint.
+const podDumpFile = "pod.dump"
+
+// podMountsFile records the shared volume paths bind-mounted across more
+// than one container in the pod, so restore can recreate them consistently
+// instead of relying on each container's own bind.mounts file.
+const podMountsFile = "pod.mounts"
+
+// PodDump describes a whole-pod checkpoint: the sandbox's ID and name, its
+// infra container, and the order its containers must be restored in.
+// PodCheckpoint does not fill Namespaces or NetworkConfig, and PodRestore
+// does not read them.
+type PodDump struct {
+	SandboxID      string `json:"sandboxID"`
+	SandboxName    string `json:"sandboxName"`
+	InfraContainer string `json:"infraContainer"`
+	// ContainerOrder lists every container ID in the pod in the order they
+	// were checkpointed (infra last); restore replays it in reverse.
+	ContainerOrder []string          `json:"containerOrder"`
+	Namespaces     map[string]string `json:"namespaces"`
+	NetworkConfig  map[string]string `json:"networkConfig"`
+}
+
+// checkpointOrder returns the pod's containers with the infra container
+// last, so it keeps serving the network namespace for the other containers
+// while they are being dumped.
+func checkpointOrder(containers []*oci.Container, infraID string) []*oci.Container {
+	ordered := make([]*oci.Container, 0, len(containers))
+	var infra *oci.Container
+	for _, c := range containers {
+		if c.ID() == infraID {
+			infra = c
+			continue
+		}
+		ordered = append(ordered, c)
+	}
+	if infra != nil {
+		ordered = append(ordered, infra)
+	}
+	return ordered
+}
+
+// containersForRestore resolves dump.ContainerOrder - the order the pod's
+// containers were checkpointed in, infra last - against the sandbox's live
+// containers, replaying it in reverse so the infra container is restored
+// first. It errors explicitly if the live container set doesn't match what
+// was checkpointed, rather than silently restoring whatever containers
+// happen to exist now in an order nothing verified.
+func containersForRestore(containers []*oci.Container, dump *PodDump) ([]*oci.Container, error) {
+	if len(dump.ContainerOrder) != len(containers) {
+		return nil, fmt.Errorf("pod %s has %d containers, but the checkpoint recorded %d",
+			dump.SandboxID, len(containers), len(dump.ContainerOrder))
+	}
+
+	byID := make(map[string]*oci.Container, len(containers))
+	for _, ctr := range containers {
+		byID[ctr.ID()] = ctr
+	}
+
+	ordered := make([]*oci.Container, len(dump.ContainerOrder))
+	for i, id := range dump.ContainerOrder {
+		ctr, ok := byID[id]
+		if !ok {
+			return nil, fmt.Errorf("container %s was checkpointed but is not present in pod %s", id, dump.SandboxID)
+		}
+		ordered[len(dump.ContainerOrder)-1-i] = ctr
+	}
+
+	return ordered, nil
+}
+
+// PodCheckpoint checkpoints every container of a pod into a single outer tar
+// archive, containing one inner checkpoint archive per container plus a
+// pod.dump describing the sandbox and a pod.mounts describing shared
+// volumes.
+func (c *ContainerServer) PodCheckpoint(ctx context.Context, opts *ContainerCheckpointRestoreOptions) (string, error) {
+	sb := c.GetSandbox(opts.Pod)
+	if sb == nil {
+		return "", fmt.Errorf("failed to find pod sandbox %s", opts.Pod)
+	}
+
+	containers := sb.Containers().List()
+	infra := sb.InfraContainer()
+	if infra == nil {
+		return "", fmt.Errorf("pod sandbox %s has no infra container", opts.Pod)
+	}
+
+	workDir, err := os.MkdirTemp("", "crio-pod-checkpoint-")
+	if err != nil {
+		return "", fmt.Errorf("failed to create pod checkpoint work dir: %w", err)
+	}
+	defer os.RemoveAll(workDir)
+
+	dump := &PodDump{
+		SandboxID:      sb.ID(),
+		SandboxName:    sb.Name(),
+		InfraContainer: infra.ID(),
+	}
+
+	for _, ctr := range checkpointOrder(containers, infra.ID()) {
+		dump.ContainerOrder = append(dump.ContainerOrder, ctr.ID())
+
+		innerArchive := filepath.Join(workDir, ctr.ID()+".tar")
+		ctrOpts := *opts
+		ctrOpts.Container = ctr.ID()
+		ctrOpts.TargetFile = innerArchive
+		ctrOpts.Pod = ""
+
+		if _, err := c.ContainerCheckpoint(ctx, &ctrOpts); err != nil {
+			return "", fmt.Errorf("failed to checkpoint container %s of pod %s: %w", ctr.ID(), opts.Pod, err)
+		}
+	}
+
+	if _, err := metadata.WriteJSONFile(dump, workDir, podDumpFile); err != nil {
+		return "", fmt.Errorf("failed to write pod dump for %s: %w", opts.Pod, err)
+	}
+
+	shared, err := sharedBindMounts(containers)
+	if err != nil {
+		return "", fmt.Errorf("failed to determine shared volumes for pod %s: %w", opts.Pod, err)
+	}
+	if _, err := metadata.WriteJSONFile(shared, workDir, podMountsFile); err != nil {
+		return "", fmt.Errorf("failed to write pod mounts for %s: %w", opts.Pod, err)
+	}
+
+	if err := tarPodCheckpoint(workDir, dump, opts.TargetFile); err != nil {
+		return "", fmt.Errorf("failed to write pod checkpoint archive for %s: %w", opts.Pod, err)
+	}
+
+	return sb.ID(), nil
+}
+
+// sharedBindMounts returns the bind mount sources used by more than one
+// container in the pod, i.e. the volumes that need to exist before any
+// container referencing them is restored. It reads the bind.mounts file
+// each container's prepareCheckpointExport call left behind.
+func sharedBindMounts(containers []*oci.Container) ([]string, error) {
+	seen := map[string]int{}
+	for _, ctr := range containers {
+		raw, err := os.ReadFile(filepath.Join(ctr.Dir(), "bind.mounts"))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+
+		var mounts []ExternalBindMount
+		if err := json.Unmarshal(raw, &mounts); err != nil {
+			return nil, err
+		}
+		for _, m := range mounts {
+			seen[m.Source]++
+		}
+	}
+
+	var shared []string
+	for source, count := range seen {
+		if count > 1 {
+			shared = append(shared, source)
+		}
+	}
+	return shared, nil
+}
+
+// recreateSharedBindMounts ensures every shared bind mount source recorded
+// in podMountsFile exists before any container of the pod is restored.
+// Missing sources are created as directories, since podMountsFile does not
+// record file types; paths that already exist are left as they are.
+func recreateSharedBindMounts(sources []string) error {
+	for _, source := range sources {
+		if _, err := os.Stat(source); err == nil {
+			continue
+		} else if !os.IsNotExist(err) {
+			return err
+		}
+		if err := os.MkdirAll(source, 0o755); err != nil {
+			return fmt.Errorf("failed to recreate shared volume %q: %w", source, err)
+		}
+	}
+	return nil
+}
+
+func tarPodCheckpoint(workDir string, dump *PodDump, export string) error {
+	includeFiles := []string{podDumpFile, podMountsFile}
+	for _, id := range dump.ContainerOrder {
+		includeFiles = append(includeFiles, id+".tar")
+	}
+
+	input, err := archive.TarWithOptions(workDir, &archive.TarOptions{
+		Compression:      archive.Uncompressed,
+		IncludeSourceDir: true,
+		IncludeFiles:     includeFiles,
+	})
+	if err != nil {
+		return err
+	}
+
+	outFile, err := os.OpenFile(export, os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return err
+	}
+	defer outFile.Close()
+
+	_, err = io.Copy(outFile, input)
+	return err
+}
+
+// PodRestore restores every container of a pod from a whole-pod checkpoint
+// archive produced by PodCheckpoint, restoring the infra container first and
+// rolling back any container already restored if a later one fails.
+func (c *ContainerServer) PodRestore(ctx context.Context, opts *ContainerCheckpointRestoreOptions) (string, error) {
+	workDir, err := os.MkdirTemp("", "crio-pod-restore-")
+	if err != nil {
+		return "", fmt.Errorf("failed to create pod restore work dir: %w", err)
+	}
+	defer os.RemoveAll(workDir)
+
+	if err := archive.UntarPath(opts.TargetFile, workDir); err != nil {
+		return "", fmt.Errorf("failed to unpack pod checkpoint archive: %w", err)
+	}
+
+	dump := &PodDump{}
+	if err := metadata.ReadJSONFile(dump, workDir, podDumpFile); err != nil {
+		return "", fmt.Errorf("failed to read pod dump: %w", err)
+	}
+
+	var sharedMounts []string
+	if err := metadata.ReadJSONFile(&sharedMounts, workDir, podMountsFile); err != nil {
+		return "", fmt.Errorf("failed to read pod mounts: %w", err)
+	}
+	if err := recreateSharedBindMounts(sharedMounts); err != nil {
+		return "", fmt.Errorf("failed to recreate shared volumes for pod %s: %w", opts.Pod, err)
+	}
+
+	sb := c.GetSandbox(opts.Pod)
+	if sb == nil {
+		return "", fmt.Errorf("failed to find pod sandbox %s", opts.Pod)
+	}
+
+	containers := sb.Containers().List()
+
+	ordered, err := containersForRestore(containers, dump)
+	if err != nil {
+		return "", fmt.Errorf("failed to determine restore order for pod %s: %w", opts.Pod, err)
+	}
+
+	var restored []*oci.Container
+	for _, ctr := range ordered {
+		innerArchive := filepath.Join(workDir, ctr.ID()+".tar")
+		if _, err := os.Stat(innerArchive); err != nil {
+			c.rollbackPodRestore(ctx, restored)
+			return "", fmt.Errorf("failed to find checkpoint archive for container %s: %w", ctr.ID(), err)
+		}
+
+		ctrOpts := *opts
+		ctrOpts.Container = ctr.ID()
+		ctrOpts.TargetFile = innerArchive
+		ctrOpts.Pod = ""
+
+		if _, err := c.ContainerRestore(ctx, &ctrOpts); err != nil {
+			c.rollbackPodRestore(ctx, restored)
+			return "", fmt.Errorf("failed to restore container %s of pod %s: %w", ctr.ID(), opts.Pod, err)
+		}
+		restored = append(restored, ctr)
+	}
+
+	return sb.ID(), nil
+}
+
+// rollbackPodRestore stops every already-restored container of a failed
+// PodRestore, in the reverse order they were restored, so a partially
+// restored pod is never left running.
+func (c *ContainerServer) rollbackPodRestore(ctx context.Context, restored []*oci.Container) {
+	for i := len(restored) - 1; i >= 0; i-- {
+		ctr := restored[i]
+		if err := c.runtime.StopContainer(ctx, ctr, int64(10)); err != nil {
+			logrus.Warnf("Unable to roll back restored container %s: %v", ctr.ID(), err)
+		}
+	}
+}
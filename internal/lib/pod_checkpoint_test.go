@@ -0,0 +1,112 @@
+package lib
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cri-o/cri-o/internal/oci"
+)
+
+func newTestContainer(t *testing.T, id string) *oci.Container {
+	t.Helper()
+	return oci.NewContainer(id, "ctr-"+id, "", t.TempDir(), "", "sandbox", time.Time{})
+}
+
+func TestCheckpointOrder(t *testing.T) {
+	infra := newTestContainer(t, "infra")
+	a := newTestContainer(t, "a")
+	b := newTestContainer(t, "b")
+
+	ordered := checkpointOrder([]*oci.Container{infra, a, b}, infra.ID())
+
+	if len(ordered) != 3 {
+		t.Fatalf("expected 3 containers, got %d", len(ordered))
+	}
+	if ordered[len(ordered)-1].ID() != infra.ID() {
+		t.Errorf("expected infra container last, got %q", ordered[len(ordered)-1].ID())
+	}
+	if ordered[0].ID() != a.ID() || ordered[1].ID() != b.ID() {
+		t.Errorf("expected non-infra containers to keep their relative order, got %v", ids(ordered))
+	}
+}
+
+func TestContainersForRestore(t *testing.T) {
+	infra := newTestContainer(t, "infra")
+	a := newTestContainer(t, "a")
+	b := newTestContainer(t, "b")
+
+	t.Run("reverses the checkpointed order, infra first", func(t *testing.T) {
+		dump := &PodDump{SandboxID: "pod", ContainerOrder: []string{a.ID(), b.ID(), infra.ID()}}
+
+		ordered, err := containersForRestore([]*oci.Container{infra, a, b}, dump)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(ordered) != 3 {
+			t.Fatalf("expected 3 containers, got %d", len(ordered))
+		}
+		if ordered[0].ID() != infra.ID() {
+			t.Errorf("expected infra container first, got %q", ordered[0].ID())
+		}
+		if ordered[1].ID() != b.ID() || ordered[2].ID() != a.ID() {
+			t.Errorf("expected containers in reverse checkpoint order, got %v", ids(ordered))
+		}
+	})
+
+	t.Run("errors when the live container count doesn't match the checkpoint", func(t *testing.T) {
+		dump := &PodDump{SandboxID: "pod", ContainerOrder: []string{a.ID(), infra.ID()}}
+
+		if _, err := containersForRestore([]*oci.Container{infra, a, b}, dump); err == nil {
+			t.Fatal("expected an error for a mismatched container count, got none")
+		}
+	})
+
+	t.Run("errors when a checkpointed container is missing from the pod", func(t *testing.T) {
+		dump := &PodDump{SandboxID: "pod", ContainerOrder: []string{a.ID(), "gone", infra.ID()}}
+
+		if _, err := containersForRestore([]*oci.Container{infra, a, b}, dump); err == nil {
+			t.Fatal("expected an error for a missing container, got none")
+		}
+	})
+}
+
+func ids(containers []*oci.Container) []string {
+	out := make([]string, len(containers))
+	for i, c := range containers {
+		out[i] = c.ID()
+	}
+	return out
+}
+
+func writeBindMounts(t *testing.T, ctr *oci.Container, mounts []ExternalBindMount) {
+	t.Helper()
+	encoded, err := json.Marshal(mounts)
+	if err != nil {
+		t.Fatalf("failed to marshal bind mounts: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(ctr.Dir(), "bind.mounts"), encoded, 0o600); err != nil {
+		t.Fatalf("failed to write bind.mounts: %v", err)
+	}
+}
+
+func TestSharedBindMounts(t *testing.T) {
+	a := newTestContainer(t, "a")
+	b := newTestContainer(t, "b")
+	c := newTestContainer(t, "c")
+
+	writeBindMounts(t, a, []ExternalBindMount{{Source: "/shared", Destination: "/data"}, {Source: "/only-a", Destination: "/only"}})
+	writeBindMounts(t, b, []ExternalBindMount{{Source: "/shared", Destination: "/data"}})
+	// c has no bind.mounts file at all, which must be tolerated.
+
+	shared, err := sharedBindMounts([]*oci.Container{a, b, c})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(shared) != 1 || shared[0] != "/shared" {
+		t.Fatalf("expected only /shared to be reported, got %v", shared)
+	}
+}
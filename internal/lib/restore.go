@@ -0,0 +1,146 @@
+package lib
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	metadata "github.com/checkpoint-restore/checkpointctl/lib"
+	"github.com/containers/storage/pkg/archive"
+	"github.com/sirupsen/logrus"
+)
+
+// parentArchiveDir is the subdirectory a previous pre-checkpoint archive is
+// unpacked into before CRIU is pointed at it via --prev-images-dir.
+const parentArchiveDir = "parent"
+
+// detectCompression sniffs the magic bytes of a checkpoint archive rather
+// than trusting its file extension, so archives can be renamed or piped
+// through tooling without losing their compression algorithm.
+func detectCompression(path string) (archive.Compression, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return archive.Uncompressed, err
+	}
+	defer f.Close()
+
+	header := make([]byte, 10)
+	n, err := f.Read(header)
+	if err != nil && err != io.EOF {
+		return archive.Uncompressed, err
+	}
+
+	return archive.DetectCompression(header[:n]), nil
+}
+
+// verifyRecordedCompression compares the compression algorithm
+// detectCompression sniffed from the checkpoint archive against the one
+// prepareCheckpointExport recorded in the container's config dump, catching
+// an archive whose compression was changed (or which was corrupted) after
+// it was exported. Archives without a config dump are skipped; an empty
+// recorded Compression is compared as uncompressed.
+func verifyRecordedCompression(dir string, detected archive.Compression) error {
+	var config metadata.ContainerConfig
+	if err := metadata.ReadJSONFile(&config, dir, metadata.ConfigDumpFile); err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	want, err := compressionFromString(config.Compression)
+	if err != nil {
+		return err
+	}
+	if want != detected {
+		return fmt.Errorf("checkpoint archive compression %v does not match %v recorded at export time", detected, want)
+	}
+
+	return nil
+}
+
+// verifyIgnoreOptions cross-checks the IgnoreRootFS/IgnoreVolumes flags
+// recorded in the container's config dump against the options requested for
+// this restore. A restore must explicitly acknowledge (by setting the same
+// flag) that the archive omits rootfs or volume data, so it fails clearly
+// instead of silently restoring a container with missing filesystem state.
+func verifyIgnoreOptions(dir string, opts *ContainerCheckpointRestoreOptions) error {
+	var config metadata.ContainerConfig
+	if err := metadata.ReadJSONFile(&config, dir, metadata.ConfigDumpFile); err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	if config.IgnoreRootFS && !opts.IgnoreRootFS {
+		return fmt.Errorf("checkpoint archive was exported with IgnoreRootFS, pass IgnoreRootFS to acknowledge the rootfs will not be restored")
+	}
+	if config.IgnoreVolumes && !opts.IgnoreVolumes {
+		return fmt.Errorf("checkpoint archive was exported with IgnoreVolumes, pass IgnoreVolumes to acknowledge named volumes will not be restored")
+	}
+
+	return nil
+}
+
+// ContainerRestore restores a container from a checkpoint archive.
+func (c *ContainerServer) ContainerRestore(ctx context.Context, opts *ContainerCheckpointRestoreOptions) (string, error) {
+	ctr, err := c.LookupContainer(opts.Container)
+	if err != nil {
+		return "", fmt.Errorf("failed to find container %s: %w", opts.Container, err)
+	}
+
+	if opts.ImportPrevious != "" && !c.runtime.RuntimeSupportsCheckpointPreDump(ctr.Sandbox()) {
+		return "", fmt.Errorf("runtime for container %s does not support CRIU pre-dump", ctr.ID())
+	}
+
+	if opts.ImportPrevious != "" {
+		parentDir := filepath.Join(ctr.Dir(), parentArchiveDir)
+		if err := os.MkdirAll(parentDir, 0o700); err != nil {
+			return "", fmt.Errorf("failed to create parent image directory for container %s: %w", ctr.ID(), err)
+		}
+		if err := archive.UntarPath(opts.ImportPrevious, parentDir); err != nil {
+			return "", fmt.Errorf("failed to unpack previous checkpoint for container %s: %w", ctr.ID(), err)
+		}
+	}
+
+	compression, err := detectCompression(opts.TargetFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to detect compression of checkpoint archive for container %s: %w", ctr.ID(), err)
+	}
+
+	if err := archive.UntarPath(opts.TargetFile, ctr.Dir()); err != nil {
+		return "", fmt.Errorf("failed to unpack checkpoint archive for container %s: %w", ctr.ID(), err)
+	}
+
+	if err := verifyCheckpointManifest(ctr.Dir(), c.config.CheckpointVerificationKey); err != nil {
+		return "", fmt.Errorf("failed to verify checkpoint archive for container %s: %w", ctr.ID(), err)
+	}
+
+	if err := verifyRecordedCompression(ctr.Dir(), compression); err != nil {
+		return "", fmt.Errorf("failed to verify checkpoint archive for container %s: %w", ctr.ID(), err)
+	}
+
+	if err := verifyIgnoreOptions(ctr.Dir(), opts); err != nil {
+		return "", fmt.Errorf("failed to verify checkpoint archive for container %s: %w", ctr.ID(), err)
+	}
+
+	parentImage := opts.ParentImage
+	if parentImage == "" {
+		if _, err := os.Stat(filepath.Join(ctr.Dir(), parentDumpFile)); err == nil {
+			parentImage = filepath.Join(ctr.Dir(), parentArchiveDir)
+		}
+	}
+
+	if err := c.runtime.RestoreContainer(ctx, ctr, parentImage); err != nil {
+		return "", fmt.Errorf("failed to restore container %s: %w", ctr.ID(), err)
+	}
+
+	if err := c.ContainerStateToDisk(ctx, ctr); err != nil {
+		logrus.Warnf("Unable to write containers %s state to disk: %v", ctr.ID(), err)
+	}
+
+	return ctr.ID(), nil
+}
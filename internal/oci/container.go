@@ -0,0 +1,73 @@
+package oci
+
+import (
+	"os"
+	"time"
+)
+
+// ContainerStateType is one of the lifecycle states CRI-O tracks for a
+// container.
+type ContainerStateType string
+
+const (
+	ContainerStateCreated ContainerStateType = "created"
+	ContainerStateRunning ContainerStateType = "running"
+	ContainerStatePaused  ContainerStateType = "paused"
+	ContainerStateStopped ContainerStateType = "stopped"
+)
+
+// ContainerState is the last runtime state CRI-O observed for a container.
+type ContainerState struct {
+	Status ContainerStateType `json:"status"`
+	Pid    int                `json:"pid"`
+}
+
+// Container is CRI-O's in-memory handle for a single OCI container.
+type Container struct {
+	id         string
+	name       string
+	bundlePath string
+	dir        string
+	imageName  string
+	sandboxID  string
+	createdAt  time.Time
+	state      *ContainerState
+}
+
+// NewContainer creates a Container handle with the given identity. dir is
+// ctr.Dir(), the per-container state directory under the storage run root.
+func NewContainer(id, name, bundlePath, dir, imageName, sandboxID string, createdAt time.Time) *Container {
+	return &Container{
+		id:         id,
+		name:       name,
+		bundlePath: bundlePath,
+		dir:        dir,
+		imageName:  imageName,
+		sandboxID:  sandboxID,
+		createdAt:  createdAt,
+	}
+}
+
+func (c *Container) ID() string             { return c.id }
+func (c *Container) Name() string           { return c.name }
+func (c *Container) BundlePath() string     { return c.bundlePath }
+func (c *Container) Dir() string            { return c.dir }
+func (c *Container) ImageName() string      { return c.imageName }
+func (c *Container) Sandbox() string        { return c.sandboxID }
+func (c *Container) CreatedAt() time.Time   { return c.createdAt }
+func (c *Container) State() *ContainerState { return c.state }
+
+// SetState updates the last-observed runtime state of the container.
+func (c *Container) SetState(state *ContainerState) {
+	c.state = state
+}
+
+// SetStateAndSpoofPid sets state and, if it doesn't already carry a Pid,
+// spoofs one to the current process's so the container can be exercised in
+// tests without a real runtime process backing it.
+func (c *Container) SetStateAndSpoofPid(state *ContainerState) {
+	if state.Pid == 0 {
+		state.Pid = os.Getpid()
+	}
+	c.state = state
+}
@@ -0,0 +1,77 @@
+package oci
+
+import (
+	"context"
+	"fmt"
+
+	rspec "github.com/opencontainers/runtime-spec/specs-go"
+)
+
+// RuntimeImpl is implemented by the OCI runtime handler (runc, crun, kata,
+// ...) CRI-O was configured with, and is responsible for actually invoking
+// the runtime binary for a given operation.
+type RuntimeImpl interface {
+	PauseContainer(ctx context.Context, c *Container) error
+	UnpauseContainer(ctx context.Context, c *Container) error
+	StopContainer(ctx context.Context, c *Container, timeout int64) error
+
+	// CheckpointContainer asks the runtime to checkpoint c via CRIU. When
+	// preDump is true, only a CRIU pre-dump is taken and the container keeps
+	// running. parentImage, when non-empty, is passed to CRIU as
+	// --prev-images-dir so the dump is taken incrementally against a
+	// previous (pre-)checkpoint.
+	CheckpointContainer(ctx context.Context, c *Container, specgen *rspec.Spec, keepRunning, preDump bool, parentImage string) error
+	// RestoreContainer asks the runtime to restore c from the checkpoint
+	// already unpacked into c.Dir(). parentImage, when non-empty, is passed
+	// to CRIU as --prev-images-dir to layer the restore on top of a
+	// previously imported pre-checkpoint.
+	RestoreContainer(ctx context.Context, c *Container, parentImage string) error
+	// SupportsCheckpointPreDump reports whether this runtime handler's CRIU
+	// build is new enough to support --pre-dump.
+	SupportsCheckpointPreDump() bool
+}
+
+// Runtime dispatches container lifecycle and checkpoint/restore operations
+// to the RuntimeImpl configured for this CRI-O instance.
+type Runtime struct {
+	impl RuntimeImpl
+}
+
+// NewRuntime returns a Runtime backed by impl.
+func NewRuntime(impl RuntimeImpl) *Runtime {
+	return &Runtime{impl: impl}
+}
+
+func (r *Runtime) PauseContainer(ctx context.Context, c *Container) error {
+	return r.impl.PauseContainer(ctx, c)
+}
+
+func (r *Runtime) UnpauseContainer(ctx context.Context, c *Container) error {
+	return r.impl.UnpauseContainer(ctx, c)
+}
+
+func (r *Runtime) StopContainer(ctx context.Context, c *Container, timeout int64) error {
+	return r.impl.StopContainer(ctx, c, timeout)
+}
+
+func (r *Runtime) CheckpointContainer(ctx context.Context, c *Container, specgen *rspec.Spec, keepRunning, preDump bool, parentImage string) error {
+	if r.impl == nil {
+		return fmt.Errorf("no runtime handler configured for container %s", c.ID())
+	}
+	return r.impl.CheckpointContainer(ctx, c, specgen, keepRunning, preDump, parentImage)
+}
+
+func (r *Runtime) RestoreContainer(ctx context.Context, c *Container, parentImage string) error {
+	if r.impl == nil {
+		return fmt.Errorf("no runtime handler configured for container %s", c.ID())
+	}
+	return r.impl.RestoreContainer(ctx, c, parentImage)
+}
+
+// RuntimeSupportsCheckpointPreDump reports whether the runtime handler
+// assigned to sandboxID supports CRIU pre-dump. sandboxID is accepted for
+// forward compatibility with per-sandbox runtime handler selection; this
+// single-handler implementation applies the same answer to every sandbox.
+func (r *Runtime) RuntimeSupportsCheckpointPreDump(sandboxID string) bool {
+	return r.impl != nil && r.impl.SupportsCheckpointPreDump()
+}
@@ -0,0 +1,231 @@
+package server
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/containers/storage/pkg/archive"
+	"github.com/containers/storage/pkg/symlink"
+	"github.com/cri-o/cri-o/internal/oci"
+	"github.com/go-zoo/bone"
+	"github.com/sirupsen/logrus"
+)
+
+// archiveProtectedPaths are container-managed mount destinations that must
+// never be read from or written to via the archive endpoint, or anything
+// below them. Unlike the set CRI-O excludes from checkpoint rootfs diffs,
+// /run itself and /run/.containerenv are not protected.
+var archiveProtectedPaths = []string{
+	"/dev",
+	"/proc",
+	"/sys",
+	"/run/secrets",
+}
+
+func isProtectedArchivePath(path string) bool {
+	clean := filepath.Clean(path)
+	for _, protected := range archiveProtectedPaths {
+		if clean == protected || strings.HasPrefix(clean, protected+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// containerPathStat is base64-encoded as JSON into the
+// X-CRIO-Container-Path-Stat header of GET archive responses, mirroring the
+// ecosystem's container-copy API.
+type containerPathStat struct {
+	Name       string      `json:"name"`
+	Size       int64       `json:"size"`
+	Mode       os.FileMode `json:"mode"`
+	Mtime      time.Time   `json:"mtime"`
+	LinkTarget string      `json:"linkTarget"`
+}
+
+// resolveArchivePath resolves path against the container's rootfs as mounted
+// from storage, refusing to cross into container-managed mounts or, via
+// symlinks or "..", out of the container's rootfs entirely. Mounts that exist
+// only inside the container's mount namespace (volumes, bind mounts) are not
+// seen through this path. On success it returns the
+// resolved host path and a cleanup func the caller must invoke (typically
+// via defer) to unmount the container once the archive request is done.
+func (s *Server) resolveArchivePath(ctr *oci.Container, path string) (hostPath string, cleanup func(), err error) {
+	if isProtectedArchivePath(path) {
+		return "", nil, fmt.Errorf("refusing to access container-managed path %q", path)
+	}
+
+	store := s.StorageImageServer().GetStore()
+	mountPoint, err := store.Mount(ctr.ID(), "")
+	if err != nil {
+		return "", nil, fmt.Errorf("unable to mount container %s: %w", ctr.ID(), err)
+	}
+	cleanup = func() {
+		if _, err := store.Unmount(ctr.ID(), false); err != nil {
+			logrus.Errorf("Failed to unmount container %s: %v", ctr.ID(), err)
+		}
+	}
+
+	// FollowSymlinkInScope resolves path (including any symlinks) without
+	// ever escaping mountPoint, unlike a plain filepath.Join which would
+	// clean "../../.." straight out of the container's rootfs.
+	hostPath, err = symlink.FollowSymlinkInScope(filepath.Join(mountPoint, path), mountPoint)
+	if err != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("unable to resolve path %q in container %s: %w", path, ctr.ID(), err)
+	}
+
+	rel, err := filepath.Rel(mountPoint, hostPath)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		cleanup()
+		return "", nil, fmt.Errorf("refusing to access path %q outside of container %s", path, ctr.ID())
+	}
+	if isProtectedArchivePath(string(filepath.Separator) + rel) {
+		cleanup()
+		return "", nil, fmt.Errorf("refusing to access container-managed path %q", path)
+	}
+
+	return hostPath, cleanup, nil
+}
+
+func writePathStatHeader(w http.ResponseWriter, hostPath, containerPath string) error {
+	info, err := os.Lstat(hostPath)
+	if err != nil {
+		return err
+	}
+
+	stat := containerPathStat{
+		Name:  filepath.Base(containerPath),
+		Size:  info.Size(),
+		Mode:  info.Mode(),
+		Mtime: info.ModTime(),
+	}
+	if info.Mode()&os.ModeSymlink != 0 {
+		if target, err := os.Readlink(hostPath); err == nil {
+			stat.LinkTarget = target
+		}
+	}
+
+	encoded, err := json.Marshal(stat)
+	if err != nil {
+		return err
+	}
+	w.Header().Set("X-CRIO-Container-Path-Stat", base64.StdEncoding.EncodeToString(encoded))
+
+	return nil
+}
+
+// getContainerArchive streams a tar of path from a running container's
+// rootfs. Modeled on the ecosystem's container-copy GET API.
+func (s *Server) getContainerArchive(w http.ResponseWriter, r *http.Request) {
+	id := bone.GetValue(r, "id")
+	path := r.URL.Query().Get("path")
+	if path == "" {
+		http.Error(w, "missing path query parameter", http.StatusBadRequest)
+		return
+	}
+
+	ctr, err := s.LookupContainer(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	hostPath, cleanup, err := s.resolveArchivePath(ctr, path)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	defer cleanup()
+
+	if _, err := os.Lstat(hostPath); err != nil {
+		if os.IsNotExist(err) {
+			http.Error(w, fmt.Sprintf("path %q not found in container %s", path, id), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := writePathStatHeader(w, hostPath, path); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	tarStream, err := archive.Tar(hostPath, archive.Uncompressed)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer tarStream.Close()
+
+	w.Header().Set("Content-Type", "application/x-tar")
+	w.WriteHeader(http.StatusOK)
+	if _, err := io.Copy(w, tarStream); err != nil {
+		logrus.Errorf("Failed to stream archive for container %s path %q: %v", id, path, err)
+	}
+}
+
+// putContainerArchive extracts a tar from the request body into path inside
+// a running container's rootfs. Modeled on the ecosystem's container-copy
+// PUT API. The destination path must already exist; it is never created.
+func (s *Server) putContainerArchive(w http.ResponseWriter, r *http.Request) {
+	id := bone.GetValue(r, "id")
+	path := r.URL.Query().Get("path")
+	if path == "" {
+		http.Error(w, "missing path query parameter", http.StatusBadRequest)
+		return
+	}
+
+	ctr, err := s.LookupContainer(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	hostPath, cleanup, err := s.resolveArchivePath(ctr, path)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	defer cleanup()
+
+	if _, err := os.Lstat(hostPath); err != nil {
+		if os.IsNotExist(err) {
+			http.Error(w, fmt.Sprintf("destination path %q does not exist in container %s", path, id), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Untar accepts a zero-byte stream as an empty archive, so catch an
+	// empty body here.
+	body := bufio.NewReader(r.Body)
+	if _, err := body.Peek(1); err == io.EOF {
+		http.Error(w, "empty archive", http.StatusRequestedRangeNotSatisfiable)
+		return
+	}
+
+	if err := archive.Untar(body, hostPath, &archive.TarOptions{}); err != nil {
+		switch {
+		case err == io.EOF:
+			http.Error(w, "empty archive", http.StatusRequestedRangeNotSatisfiable)
+		case os.IsPermission(err):
+			http.Error(w, err.Error(), http.StatusForbidden)
+		default:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
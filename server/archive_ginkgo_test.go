@@ -0,0 +1,162 @@
+package server_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+
+	"github.com/go-zoo/bone"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// minimalTarArchive builds a single-entry tar archive, so PUT requests that
+// need to exercise the actual Untar codepath (rather than the empty-body
+// shortcut) have something to extract.
+func minimalTarArchive() []byte {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	content := []byte("hello")
+	if err := tw.WriteHeader(&tar.Header{
+		Name: "file.txt",
+		Mode: 0o600,
+		Size: int64(len(content)),
+	}); err != nil {
+		panic(err)
+	}
+	if _, err := tw.Write(content); err != nil {
+		panic(err)
+	}
+	if err := tw.Close(); err != nil {
+		panic(err)
+	}
+	return buf.Bytes()
+}
+
+var _ = t.Describe("Archive", func() {
+	var (
+		recorder *httptest.ResponseRecorder
+		mux      *bone.Mux
+	)
+
+	BeforeEach(func() {
+		beforeEach()
+		mockRuncInLibConfig()
+		setupSUT()
+
+		recorder = httptest.NewRecorder()
+		mux = sut.GetExtendInterfaceMux(false)
+		Expect(mux).NotTo(BeNil())
+		Expect(recorder).NotTo(BeNil())
+	})
+	AfterEach(afterEach)
+
+	t.Describe("GetExtendInterfaceMux", func() {
+		It("should fail with unknown container on GET /containers/{id}/archive", func() {
+			// Given
+			// When
+			request, err := http.NewRequest(http.MethodGet,
+				"/containers/doesnotexist/archive?path=/tmp", http.NoBody)
+
+			// Then
+			Expect(err).To(BeNil())
+			mux.ServeHTTP(recorder, request)
+			Expect(recorder.Code).To(BeEquivalentTo(http.StatusNotFound))
+		})
+
+		It("should refuse a container-managed path on GET /containers/{id}/archive", func() {
+			// Given
+			addContainerAndSandbox()
+
+			// When
+			request, err := http.NewRequest(http.MethodGet,
+				"/containers/"+testContainer.ID()+"/archive?path=/proc/1", http.NoBody)
+
+			// Then
+			Expect(err).To(BeNil())
+			mux.ServeHTTP(recorder, request)
+			Expect(recorder.Code).To(BeEquivalentTo(http.StatusForbidden))
+		})
+
+		It("should refuse a path-traversal attempt on GET /containers/{id}/archive", func() {
+			// Given
+			addContainerAndSandbox()
+
+			// When
+			request, err := http.NewRequest(http.MethodGet,
+				"/containers/"+testContainer.ID()+"/archive?path=/../../../../../../etc/passwd", http.NoBody)
+
+			// Then
+			Expect(err).To(BeNil())
+			mux.ServeHTTP(recorder, request)
+			Expect(recorder.Code).To(BeEquivalentTo(http.StatusForbidden))
+		})
+
+		It("should fail with an empty tar on PUT /containers/{id}/archive", func() {
+			// Given
+			addContainerAndSandbox()
+
+			// When
+			request, err := http.NewRequest(http.MethodPut,
+				"/containers/"+testContainer.ID()+"/archive?path=/tmp",
+				bytes.NewReader([]byte{}))
+
+			// Then
+			Expect(err).To(BeNil())
+			mux.ServeHTTP(recorder, request)
+			Expect(recorder.Code).To(BeEquivalentTo(http.StatusRequestedRangeNotSatisfiable))
+		})
+
+		It("should refuse a write into a container-managed path on PUT /containers/{id}/archive", func() {
+			// Given
+			addContainerAndSandbox()
+
+			// When
+			request, err := http.NewRequest(http.MethodPut,
+				"/containers/"+testContainer.ID()+"/archive?path=/run/secrets",
+				bytes.NewReader([]byte{}))
+
+			// Then
+			Expect(err).To(BeNil())
+			mux.ServeHTTP(recorder, request)
+			Expect(recorder.Code).To(BeEquivalentTo(http.StatusForbidden))
+		})
+
+		It("should fail with unknown container on PUT /containers/{id}/archive", func() {
+			// Given
+			// When
+			request, err := http.NewRequest(http.MethodPut,
+				"/containers/doesnotexist/archive?path=/tmp", bytes.NewReader([]byte{}))
+
+			// Then
+			Expect(err).To(BeNil())
+			mux.ServeHTTP(recorder, request)
+			Expect(recorder.Code).To(BeEquivalentTo(http.StatusNotFound))
+		})
+
+		It("should fail with permission denied on PUT /containers/{id}/archive", func() {
+			// Given
+			if os.Geteuid() == 0 {
+				Skip("directory permission bits don't deny root")
+			}
+			addContainerAndSandbox()
+
+			dir, err := os.MkdirTemp("", "cri-o-archive-readonly-")
+			Expect(err).To(BeNil())
+			defer os.RemoveAll(dir)
+			Expect(os.Chmod(dir, 0o555)).To(BeNil())
+
+			// When
+			request, err := http.NewRequest(http.MethodPut,
+				"/containers/"+testContainer.ID()+"/archive?path="+dir,
+				bytes.NewReader(minimalTarArchive()))
+
+			// Then
+			Expect(err).To(BeNil())
+			mux.ServeHTTP(recorder, request)
+			Expect(recorder.Code).To(BeEquivalentTo(http.StatusForbidden))
+		})
+	})
+})
@@ -0,0 +1,157 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/pprof"
+	"runtime/debug"
+
+	"github.com/cri-o/cri-o/internal/oci"
+	"github.com/go-zoo/bone"
+	"github.com/sirupsen/logrus"
+)
+
+// GetExtendInterfaceMux returns the mux used to serve CRI-O's debug and
+// introspection HTTP endpoints, which live outside of the gRPC CRI API:
+// container inspection, pause/unpause, filesystem archive access and (when
+// enabled) Go's pprof profiling endpoints.
+func (s *Server) GetExtendInterfaceMux(enableProfilingEndpoint bool) *bone.Mux {
+	mux := bone.New()
+
+	mux.GetFunc("/info", s.recover(s.getInfo))
+	mux.GetFunc("/containers/:id", s.recover(s.getContainerInfo))
+	mux.GetFunc("/pause/:id", s.recover(s.getContainerPause))
+	mux.GetFunc("/unpause/:id", s.recover(s.getContainerUnpause))
+	mux.GetFunc("/containers/:id/archive", s.recover(s.getContainerArchive))
+	mux.PutFunc("/containers/:id/archive", s.recover(s.putContainerArchive))
+	mux.PostFunc("/pods/:id/checkpoint", s.recover(s.postPodCheckpoint))
+	mux.PostFunc("/pods/:id/restore", s.recover(s.postPodRestore))
+
+	if enableProfilingEndpoint {
+		addProfilingEndpoints(mux)
+	}
+
+	return mux
+}
+
+// addProfilingEndpoints wires up Go's standard pprof handlers so operators
+// can opt in to runtime profiling without exposing it by default.
+func addProfilingEndpoints(mux *bone.Mux) {
+	mux.GetFunc("/debug/pprof/", pprof.Index)
+	mux.GetFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.GetFunc("/debug/pprof/profile", pprof.Profile)
+	mux.GetFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.GetFunc("/debug/pprof/trace", pprof.Trace)
+}
+
+// recover wraps an http.HandlerFunc so that a panic deep in the container
+// lookup chain (for example, inspecting a container whose state has not
+// been populated yet) turns into a 500 rather than taking down the server.
+func (s *Server) recover(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			if err := recover(); err != nil {
+				logrus.Errorf("Recovered from panic handling %s: %v\n%s", r.URL.Path, err, debug.Stack())
+				http.Error(w, fmt.Sprintf("internal error: %v", err), http.StatusInternalServerError)
+			}
+		}()
+		next(w, r)
+	}
+}
+
+func (s *Server) getInfo(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(s.ContainerServer.Config()); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (s *Server) getContainerInfo(w http.ResponseWriter, r *http.Request) {
+	id := bone.GetValue(r, "id")
+
+	ctr, err := s.LookupContainer(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	sb := s.GetSandbox(ctr.Sandbox())
+	if sb == nil {
+		http.Error(w, fmt.Sprintf("unable to find sandbox for container %s", id), http.StatusNotFound)
+		return
+	}
+
+	state := ctr.State()
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(struct {
+		Name      string              `json:"name"`
+		Pid       int                 `json:"pid"`
+		Image     string              `json:"image"`
+		State     *oci.ContainerState `json:"state"`
+		SandboxID string              `json:"sandboxID"`
+	}{
+		Name:      ctr.Name(),
+		Pid:       state.Pid,
+		Image:     ctr.ImageName(),
+		State:     state,
+		SandboxID: sb.ID(),
+	}); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (s *Server) getContainerPause(w http.ResponseWriter, r *http.Request) {
+	id := bone.GetValue(r, "id")
+
+	ctr, err := s.LookupContainer(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	if ctr.State().Status == oci.ContainerStatePaused {
+		http.Error(w, fmt.Sprintf("container %s is already paused", id), http.StatusConflict)
+		return
+	}
+
+	if err := s.Runtime().PauseContainer(r.Context(), ctr); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := s.ContainerStateToDisk(r.Context(), ctr); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) getContainerUnpause(w http.ResponseWriter, r *http.Request) {
+	id := bone.GetValue(r, "id")
+
+	ctr, err := s.LookupContainer(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	if ctr.State().Status != oci.ContainerStatePaused {
+		http.Error(w, fmt.Sprintf("container %s is not paused", id), http.StatusConflict)
+		return
+	}
+
+	if err := s.Runtime().UnpauseContainer(r.Context(), ctr); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := s.ContainerStateToDisk(r.Context(), ctr); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
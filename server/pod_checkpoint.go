@@ -0,0 +1,55 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/cri-o/cri-o/internal/lib"
+	"github.com/go-zoo/bone"
+)
+
+// postPodCheckpoint checkpoints every container of a pod into a single
+// archive at the path given by the "file" query parameter.
+func (s *Server) postPodCheckpoint(w http.ResponseWriter, r *http.Request) {
+	id := bone.GetValue(r, "id")
+	target := r.URL.Query().Get("file")
+	if target == "" {
+		http.Error(w, "missing file query parameter", http.StatusBadRequest)
+		return
+	}
+
+	opts := &lib.ContainerCheckpointRestoreOptions{
+		Pod: id,
+	}
+	opts.TargetFile = target
+
+	if _, err := s.ContainerServer.PodCheckpoint(r.Context(), opts); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// postPodRestore restores every container of a pod from the whole-pod
+// checkpoint archive given by the "file" query parameter.
+func (s *Server) postPodRestore(w http.ResponseWriter, r *http.Request) {
+	id := bone.GetValue(r, "id")
+	source := r.URL.Query().Get("file")
+	if source == "" {
+		http.Error(w, "missing file query parameter", http.StatusBadRequest)
+		return
+	}
+
+	opts := &lib.ContainerCheckpointRestoreOptions{
+		Pod: id,
+	}
+	opts.TargetFile = source
+
+	if _, err := s.ContainerServer.PodRestore(r.Context(), opts); err != nil {
+		http.Error(w, fmt.Errorf("failed to restore pod %s: %w", id, err).Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
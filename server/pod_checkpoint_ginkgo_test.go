@@ -0,0 +1,77 @@
+package server_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/go-zoo/bone"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = t.Describe("PodCheckpoint", func() {
+	var (
+		recorder *httptest.ResponseRecorder
+		mux      *bone.Mux
+	)
+
+	BeforeEach(func() {
+		beforeEach()
+		mockRuncInLibConfig()
+		setupSUT()
+
+		recorder = httptest.NewRecorder()
+		mux = sut.GetExtendInterfaceMux(false)
+		Expect(mux).NotTo(BeNil())
+		Expect(recorder).NotTo(BeNil())
+	})
+	AfterEach(afterEach)
+
+	t.Describe("GetExtendInterfaceMux", func() {
+		It("should fail with empty on /pods/{id}/checkpoint route", func() {
+			// Given
+			// When
+			request, err := http.NewRequest(http.MethodPost, "/pods/123/checkpoint", http.NoBody)
+
+			// Then
+			Expect(err).To(BeNil())
+			mux.ServeHTTP(recorder, request)
+			Expect(recorder.Code).To(BeEquivalentTo(http.StatusBadRequest))
+		})
+
+		It("should fail with unknown pod on /pods/{id}/checkpoint route", func() {
+			// Given
+			// When
+			request, err := http.NewRequest(http.MethodPost,
+				"/pods/123/checkpoint?file=/tmp/pod.tar", http.NoBody)
+
+			// Then
+			Expect(err).To(BeNil())
+			mux.ServeHTTP(recorder, request)
+			Expect(recorder.Code).To(BeEquivalentTo(http.StatusInternalServerError))
+		})
+
+		It("should fail with empty on /pods/{id}/restore route", func() {
+			// Given
+			// When
+			request, err := http.NewRequest(http.MethodPost, "/pods/123/restore", http.NoBody)
+
+			// Then
+			Expect(err).To(BeNil())
+			mux.ServeHTTP(recorder, request)
+			Expect(recorder.Code).To(BeEquivalentTo(http.StatusBadRequest))
+		})
+
+		It("should fail with unknown pod on /pods/{id}/restore route", func() {
+			// Given
+			// When
+			request, err := http.NewRequest(http.MethodPost,
+				"/pods/123/restore?file=/tmp/pod.tar", http.NoBody)
+
+			// Then
+			Expect(err).To(BeNil())
+			mux.ServeHTTP(recorder, request)
+			Expect(recorder.Code).To(BeEquivalentTo(http.StatusInternalServerError))
+		})
+	})
+})